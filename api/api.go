@@ -0,0 +1,18 @@
+// Package api wires up the reverse_exporter control-plane HTTP API.
+package api
+
+import (
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/wrouesnel/reverse_exporter/api/apisettings"
+)
+
+// NewAPIv1 attaches the v1 API routes to the given router and returns it.
+func NewAPIv1(settings apisettings.APISettings, router *httprouter.Router) *httprouter.Router {
+	router.GET(settings.WrapPath("/healthz"), func(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	return router
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/wrouesnel/reverse_exporter/api/apisettings"
+	"github.com/wrouesnel/reverse_exporter/config"
+	"github.com/wrouesnel/reverse_exporter/metricproxy"
+	"go.uber.org/zap"
+)
+
+// dynamicProxyRouter dispatches requests to the current set of reverse-exporter
+// backend handlers, keyed by path. The handler map is swapped atomically behind
+// a mutex so a SIGHUP config reload can rebuild backends without ever dropping
+// the listener or racing with an in-flight scrape.
+type dynamicProxyRouter struct {
+	mtx      sync.RWMutex
+	handlers map[string]http.Handler
+}
+
+// newDynamicProxyRouter initializes an empty dynamicProxyRouter.
+func newDynamicProxyRouter() *dynamicProxyRouter {
+	return &dynamicProxyRouter{
+		handlers: make(map[string]http.Handler),
+	}
+}
+
+// ServeHTTP implements http.Handler, looking up the handler registered for the
+// exact request path.
+func (d *dynamicProxyRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mtx.RLock()
+	handler, found := d.handlers[r.URL.Path]
+	d.mtx.RUnlock()
+
+	if !found {
+		http.NotFound(w, r)
+		return
+	}
+
+	handler.ServeHTTP(w, r)
+}
+
+// swap atomically replaces the handler map, returning the handlers which were
+// displaced so the caller can release their resources.
+func (d *dynamicProxyRouter) swap(handlers map[string]http.Handler) map[string]http.Handler {
+	d.mtx.Lock()
+	old := d.handlers
+	d.handlers = handlers
+	d.mtx.Unlock()
+	return old
+}
+
+// buildReverseProxyHandlers constructs one handler per configured ReverseExporter.
+func buildReverseProxyHandlers(
+	reverseConfig *config.Config, apiConfig apisettings.APISettings,
+) (map[string]http.Handler, error) {
+	handlers := make(map[string]http.Handler)
+
+	for _, rp := range reverseConfig.ReverseExporters {
+		if rp.Path == "" {
+			zap.L().Fatal("Blank exporter paths are not allowed.")
+		}
+
+		if _, found := handlers[apiConfig.WrapPath(rp.Path)]; found {
+			zap.L().Fatal("Exporter paths must be unique", zap.String("already exists", rp.Path))
+		}
+
+		proxyHandler, err := metricproxy.NewMetricReverseProxy(rp)
+		if err != nil {
+			return nil, err
+		}
+
+		handlers[apiConfig.WrapPath(rp.Path)] = proxyHandler
+	}
+
+	return handlers, nil
+}
+
+// closeDisplacedHandlers releases the backends of handlers which are no longer
+// part of the active configuration, e.g. after a config reload removed them.
+func closeDisplacedHandlers(handlers map[string]http.Handler) {
+	for path, handler := range handlers {
+		closer, ok := handler.(interface{ Close() error })
+		if !ok {
+			continue
+		}
+		if err := closer.Close(); err != nil {
+			zap.L().Error("Error closing displaced reverse-exporter backend",
+				zap.String("path", path), zap.Error(err))
+		}
+	}
+}
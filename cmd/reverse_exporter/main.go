@@ -1,17 +1,18 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/wrouesnel/reverse_exporter/api"
 	"github.com/wrouesnel/reverse_exporter/api/apisettings"
 	"github.com/wrouesnel/reverse_exporter/config"
-	"github.com/wrouesnel/reverse_exporter/metricproxy"
 	"github.com/wrouesnel/reverse_exporter/version"
 	"go.uber.org/zap"
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -26,10 +27,11 @@ type AppConfig struct {
 	ContextPath string
 	StaticProxy string
 
-	ListenAddr string
-	TLSCert    string
-	TLSKey     string
-	TLSAuthCA  string
+	ListenAddr      string
+	TLSCert         string
+	TLSKey          string
+	TLSAuthCA       string
+	ShutdownTimeout time.Duration
 
 	LogLevel string
 
@@ -49,30 +51,21 @@ func realMain(appConfig AppConfig) int {
 		zap.L().Fatal("Could not parse configuration file", zap.Error(err))
 	}
 
-	// Setup the web UI
+	// Setup the web UI. Reverse-exporter paths are served by proxyRouter, a
+	// mutex-guarded indirection layer which can be rebuilt wholesale on SIGHUP
+	// without re-registering routes on router itself.
+	proxyRouter := newDynamicProxyRouter()
+
 	router := httprouter.New()
 	router = api.NewAPIv1(apiConfig, router)
+	router.NotFound = proxyRouter
 
 	zap.L().Debug("Begin initializing reverse proxy backends")
-	initializedPaths := make(map[string]http.Handler)
-	for _, rp := range reverseConfig.ReverseExporters {
-		if rp.Path == "" {
-			zap.L().Fatal("Blank exporter paths are not allowed.")
-		}
-
-		if _, found := initializedPaths[rp.Path]; found {
-			zap.L().Fatal("Exporter paths must be unique", zap.String("already exists", rp.Path))
-		}
-
-		proxyHandler, perr := metricproxy.NewMetricReverseProxy(rp)
-		if perr != nil {
-			zap.L().Fatal("Error initializing reverse proxy for path", zap.String("path", rp.Path))
-		}
-
-		router.Handler("GET", apiConfig.WrapPath(rp.Path), proxyHandler)
-
-		initializedPaths[rp.Path] = proxyHandler
+	handlers, err := buildReverseProxyHandlers(reverseConfig, apiConfig)
+	if err != nil {
+		zap.L().Fatal("Error initializing reverse proxy backends", zap.Error(err))
 	}
+	proxyRouter.swap(handlers)
 	zap.L().Debug("Finished initializing reverse proxy backends")
 	zap.L().Info("Initialized backends", zap.Int("num_reverse_endpoints", len(reverseConfig.ReverseExporters)))
 
@@ -88,7 +81,7 @@ func realMain(appConfig AppConfig) int {
 		Handler: router,
 	}
 
-	listenerErrs := make(chan error)
+	listenerErrs := make(chan error, 1)
 
 	if appConfig.TLSCert != "" && appConfig.TLSKey != "" {
 		if appConfig.TLSAuthCA != "" {
@@ -114,20 +107,78 @@ func realMain(appConfig AppConfig) int {
 		}()
 	}
 
-	// Setup signal wait for shutdown
-	shutdownCh := make(chan os.Signal, 1)
-	signal.Notify(shutdownCh, syscall.SIGINT, syscall.SIGTERM)
-
-	// If a listener fails while it's listening, we'd like to panic and shutdown
-	// since it shouldn't really happen.
-	select {
-	case sig := <-shutdownCh:
-		zap.L().Info("Terminating on signal", zap.Stringer("signal", sig))
-		return 0
-	case listenerErr := <-listenerErrs:
-		zap.L().Fatal("Terminating due to listener shutdown", zap.Error(listenerErr))
-		return 1 // just to satisfy compiler
+	// Setup signal handling: SIGINT/SIGTERM drain and shut the server down,
+	// SIGHUP reloads the config file and swaps the proxy backends in place,
+	// SIGUSR2 hands the listening socket off to a freshly exec'd replacement.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR2)
+
+	for {
+		select {
+		case sig := <-sigCh:
+			switch sig {
+			case syscall.SIGHUP:
+				zap.L().Info("Reloading configuration on signal", zap.Stringer("signal", sig))
+				reloadConfig(appConfig, apiConfig, proxyRouter)
+			case syscall.SIGUSR2:
+				zap.L().Info("Handing listener off to a replacement process", zap.Stringer("signal", sig))
+				if err := respawnSelf(listener); err != nil {
+					zap.L().Error("Listener handoff failed", zap.Error(err))
+					break
+				}
+
+				// The replacement process now holds the listener fd and is
+				// accepting on it; stop taking new connections here and drain
+				// in-flight ones, the same as a SIGINT/SIGTERM shutdown, so
+				// repeated handoffs don't pile up processes all serving the
+				// same socket.
+				zap.L().Info("Draining this process now that the replacement holds the listener")
+				ctx, cancel := context.WithTimeout(context.Background(), appConfig.ShutdownTimeout)
+				defer cancel()
+				if err := srv.Shutdown(ctx); err != nil {
+					zap.L().Error("Error during graceful shutdown after listener handoff", zap.Error(err))
+					return 1
+				}
+				return 0
+			default:
+				zap.L().Info("Terminating on signal", zap.Stringer("signal", sig))
+
+				ctx, cancel := context.WithTimeout(context.Background(), appConfig.ShutdownTimeout)
+				defer cancel()
+				if err := srv.Shutdown(ctx); err != nil {
+					zap.L().Error("Error during graceful shutdown", zap.Error(err))
+					return 1
+				}
+				return 0
+			}
+		case listenerErr := <-listenerErrs:
+			// If a listener fails while it's listening, we'd like to panic and shutdown
+			// since it shouldn't really happen.
+			zap.L().Fatal("Terminating due to listener shutdown", zap.Error(listenerErr))
+			return 1 // just to satisfy compiler
+		}
+	}
+}
+
+// reloadConfig re-reads the YAML configuration from disk, rebuilds the reverse
+// proxy backends and atomically swaps them into proxyRouter. The previous
+// generation of handlers is closed afterwards so any goroutines they own (e.g.
+// an execCachingProxy's execer) exit instead of leaking.
+func reloadConfig(appConfig AppConfig, apiConfig apisettings.APISettings, proxyRouter *dynamicProxyRouter) {
+	reverseConfig, err := config.LoadFromFile(appConfig.ConfigFile)
+	if err != nil {
+		zap.L().Error("Could not parse configuration file, keeping previous configuration", zap.Error(err))
+		return
+	}
+
+	handlers, err := buildReverseProxyHandlers(reverseConfig, apiConfig)
+	if err != nil {
+		zap.L().Error("Could not rebuild reverse proxy backends, keeping previous configuration", zap.Error(err))
+		return
 	}
+
+	old := proxyRouter.swap(handlers)
+	closeDisplacedHandlers(old)
 }
 
 func main() {
@@ -147,6 +198,8 @@ func main() {
 		Default("").StringVar(&appConfig.TLSKey)
 	app.Flag("tls.auth.ca", "Path to CA cert file to be used for TLS client cert auth. No authentication if empty.").
 		Default("").StringVar(&appConfig.TLSAuthCA)
+	app.Flag("http.shutdown-timeout", "Time to wait for in-flight scrapes to complete on SIGINT/SIGTERM before exiting").
+		Default("30s").DurationVar(&appConfig.ShutdownTimeout)
 	app.Flag("log.level", "Only log messages with the given severity or above. Valid levels: [debug info warn error dpanic panic fatal]").
 		Default("info").StringVar(&appConfig.LogLevel)
 	app.Version(version.Version)
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+
+	"github.com/pkg/errors"
+)
+
+// fileListener is implemented by the concrete net.Listener types (*net.TCPListener,
+// *net.UnixListener) which can hand their underlying file descriptor to a child process.
+type fileListener interface {
+	File() (*os.File, error)
+}
+
+// respawnSelf forks and execs a copy of the running binary with the same arguments,
+// passing it the listening socket via an extra file descriptor so the child can
+// take over accepting connections while this process drains in place. Modelled
+// after the listener handoff used for zero-downtime restarts in Teleport.
+func respawnSelf(listener net.Listener) error {
+	fl, ok := listener.(fileListener)
+	if !ok {
+		return errors.Errorf("listener of type %T does not support handoff", listener)
+	}
+
+	lf, err := fl.File()
+	if err != nil {
+		return errors.Wrap(err, "could not obtain listener file descriptor")
+	}
+	defer lf.Close() // nolint: errcheck
+
+	execPath, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "could not determine executable path")
+	}
+
+	cmd := exec.Command(execPath, os.Args[1:]...) // nolint: gas
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{lf}
+
+	// ExtraFiles[0] is always inherited as fd 3 (0=stdin, 1=stdout, 2=stderr).
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=3", envInheritedListenerFD))
+
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "could not start replacement process")
+	}
+
+	return nil
+}
@@ -4,10 +4,24 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
+// envInheritedListenerFD, when set, names the file descriptor number of a
+// listener inherited from a parent process during a SIGUSR2 handoff. uniListen
+// takes over that descriptor instead of binding a fresh one.
+const envInheritedListenerFD = "REVERSE_EXPORTER_LISTENER_FD"
+
 func uniListen(addr string) (net.Listener, error) {
+	if fdStr := os.Getenv(envInheritedListenerFD); fdStr != "" {
+		fd, err := strconv.Atoi(fdStr)
+		if err != nil {
+			return nil, err
+		}
+		return net.FileListener(os.NewFile(uintptr(fd), "inherited-listener"))
+	}
+
 	parts := strings.SplitN(addr, "://", 2)
 	if len(parts) < 2 {
 		return net.Listen("tcp", addr)
@@ -0,0 +1,295 @@
+// Package config implements parsing of the reverse_exporter YAML configuration file.
+package config
+
+import (
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Duration wraps time.Duration so it can be parsed directly from a YAML scalar like "30s".
+type Duration time.Duration
+
+// UnmarshalYAML implements yaml.Unmarshaler for Duration.
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.Wrapf(err, "invalid duration %q", s)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// AuthType enumerates the supported authentication mechanisms for a reverse exporter endpoint.
+type AuthType string
+
+const (
+	// AuthTypeNone disables authentication for an endpoint.
+	AuthTypeNone AuthType = ""
+	// AuthTypeBasic protects an endpoint with HTTP basic authentication against an htpasswd file.
+	AuthTypeBasic AuthType = "basic"
+)
+
+// Config is the root of a reverse_exporter YAML configuration file.
+type Config struct {
+	ReverseExporters []ReverseExporter `yaml:"reverse_exporters"`
+}
+
+// Exporter is implemented by every concrete exporter configuration type so the
+// reverse proxy can recover the fields common to all of them.
+type Exporter interface {
+	GetBaseExporter() BaseExporter
+}
+
+// BaseExporter holds the fields shared by every exporter configuration.
+type BaseExporter struct {
+	Name      string            `yaml:"name"`
+	NoRewrite bool              `yaml:"no_rewrite"`
+	Labels    map[string]string `yaml:"labels"`
+	// ScrapeTimeout bounds how long this backend is given to respond to a scrape
+	// before it is treated as failed. Zero means the request's own context
+	// deadline (if any) applies instead.
+	ScrapeTimeout Duration `yaml:"scrape_timeout"`
+	// CircuitBreaker, if set, wraps this backend with a closed/open/half-open
+	// circuit breaker so a consistently failing backend is failed fast instead
+	// of retried on every scrape.
+	CircuitBreaker *CircuitBreakerConfig `yaml:"circuit_breaker"`
+	// MetricRelabelConfigs filters and rewrites this backend's metrics before
+	// they are merged into the endpoint's response, the same way Prometheus's
+	// own metric_relabel_configs do at scrape time.
+	MetricRelabelConfigs []RelabelConfig `yaml:"metric_relabel_configs"`
+}
+
+// RelabelAction enumerates the metric relabeling actions a RelabelConfig may perform.
+type RelabelAction string
+
+const (
+	// RelabelReplace writes Replacement (with regex capture groups expanded) to
+	// TargetLabel if Regex matches the concatenated source label values. It is the default action.
+	RelabelReplace RelabelAction = "replace"
+	// RelabelKeep drops the metric unless Regex matches the concatenated source label values.
+	RelabelKeep RelabelAction = "keep"
+	// RelabelDrop drops the metric if Regex matches the concatenated source label values.
+	RelabelDrop RelabelAction = "drop"
+	// RelabelLabelDrop removes every label (excluding the metric name) whose name matches Regex.
+	RelabelLabelDrop RelabelAction = "labeldrop"
+	// RelabelLabelKeep removes every label (excluding the metric name) whose name does not match Regex.
+	RelabelLabelKeep RelabelAction = "labelkeep"
+	// RelabelHashMod writes the modulus of the FNV hash of the concatenated
+	// source label values to TargetLabel, e.g. for client-side sharding.
+	RelabelHashMod RelabelAction = "hashmod"
+)
+
+// RelabelConfig describes a single metric relabeling rule, applied to every
+// metric family a backend returns before it is merged into the endpoint's
+// response. It mirrors the fields of Prometheus's own relabel_config.
+type RelabelConfig struct {
+	// SourceLabels are concatenated with Separator to build the value Regex is matched against.
+	// "__name__" refers to the metric family's name.
+	SourceLabels []string `yaml:"source_labels"`
+	// Separator joins SourceLabels together. Defaults to ";".
+	Separator string `yaml:"separator"`
+	// Regex is anchored and matched against the concatenated source label
+	// values, or against each label name for labeldrop/labelkeep. Defaults to matching everything.
+	Regex string `yaml:"regex"`
+	// Modulus is the divisor used by the hashmod action.
+	Modulus uint64 `yaml:"modulus"`
+	// TargetLabel is written by the replace and hashmod actions. "__name__" renames the metric.
+	TargetLabel string `yaml:"target_label"`
+	// Replacement is the value written to TargetLabel by the replace action, expanded with Regex's capture groups.
+	Replacement string `yaml:"replacement"`
+	// Action is one of replace, keep, drop, labeldrop, labelkeep or hashmod. Defaults to replace.
+	Action RelabelAction `yaml:"action"`
+}
+
+// CircuitBreakerConfig configures the circuit breaker wrapped around a backend proxy.
+type CircuitBreakerConfig struct {
+	// WindowSize is how many of the most recent scrape outcomes are tracked to compute the failure ratio.
+	WindowSize int `yaml:"window_size"`
+	// FailureThreshold is the failure ratio, in [0, 1], within the window which opens the breaker.
+	FailureThreshold float64 `yaml:"failure_threshold"`
+	// Cooldown is how long the breaker stays open before allowing a single half-open probe scrape.
+	Cooldown Duration `yaml:"cooldown"`
+	// MaxCooldown bounds the adaptive backoff: each consecutive failed
+	// half-open probe doubles the cooldown up to this ceiling, so a backend
+	// that keeps failing every probe backs off further instead of being
+	// re-probed on the same fixed interval forever. Defaults to 10x Cooldown.
+	MaxCooldown Duration `yaml:"max_cooldown"`
+}
+
+// GetBaseExporter implements Exporter.
+func (b BaseExporter) GetBaseExporter() BaseExporter {
+	return b
+}
+
+// FileExporterConfig scrapes metrics rendered to a static file on disk.
+type FileExporterConfig struct {
+	BaseExporter `yaml:",inline"`
+	Path         string `yaml:"path"`
+}
+
+// ExecExporterConfig scrapes metrics by invoking a subprocess for every scrape.
+type ExecExporterConfig struct {
+	BaseExporter `yaml:",inline"`
+	Command      string   `yaml:"command"`
+	Args         []string `yaml:"args"`
+	// Format fixes the exposition format the script's stdout is decoded as:
+	// one of "text" (default), "openmetrics" or "protobuf".
+	Format string `yaml:"format"`
+}
+
+// ExecCachingExporterConfig scrapes metrics from a subprocess run on a fixed
+// interval, serving the most recently cached result to scrapers in between.
+type ExecCachingExporterConfig struct {
+	BaseExporter `yaml:",inline"`
+	Command      string   `yaml:"command"`
+	Args         []string `yaml:"args"`
+	ExecInterval Duration `yaml:"exec_interval"`
+	// Format fixes the exposition format the script's stdout is decoded as:
+	// one of "text" (default), "openmetrics" or "protobuf".
+	Format string `yaml:"format"`
+}
+
+// HTTPExporterConfig scrapes metrics from another Prometheus-compatible HTTP endpoint.
+type HTTPExporterConfig struct {
+	BaseExporter     `yaml:",inline"`
+	Address          string     `yaml:"address"`
+	Timeout          Duration   `yaml:"timeout"`
+	ForwardURLParams bool       `yaml:"forward_url_params"`
+	TLS              *TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig configures an optional client certificate presented when scraping
+// an https:// backend, plus the CA used to verify it.
+type TLSConfig struct {
+	// CAFile, if set, is used instead of the system root pool to verify the backend's certificate.
+	CAFile string `yaml:"ca"`
+	// CertFile and KeyFile are the client certificate presented to the backend.
+	CertFile string `yaml:"cert"`
+	KeyFile  string `yaml:"key"`
+	// ServerName overrides the SNI/verification name sent to the backend.
+	ServerName string `yaml:"server_name"`
+	// InsecureSkipVerify disables verification of the backend's certificate chain.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+	// AutoReload watches CAFile/CertFile/KeyFile for changes and reloads them without a restart.
+	AutoReload bool `yaml:"auto_reload"`
+}
+
+// OTLPExporterConfig scrapes an OTLP/HTTP metrics producer and converts the
+// response into Prometheus metric families.
+type OTLPExporterConfig struct {
+	BaseExporter `yaml:",inline"`
+	Address      string `yaml:"address"`
+	// Method is the HTTP method used to request metrics: "POST" (default, sends
+	// an empty ExportMetricsServiceRequest body) or "GET".
+	Method           string     `yaml:"method"`
+	Timeout          Duration   `yaml:"timeout"`
+	Gzip             bool       `yaml:"gzip"`
+	ForwardURLParams bool       `yaml:"forward_url_params"`
+	TLS              *TLSConfig `yaml:"tls"`
+}
+
+// ReverseExporter describes a single HTTP path which multiplexes one or more
+// backend exporters behind it.
+type ReverseExporter struct {
+	Path         string     `yaml:"path"`
+	AuthType     AuthType   `yaml:"auth_type"`
+	HtPasswdFile string     `yaml:"htpasswd_file"`
+	Exporters    []Exporter `yaml:"-"`
+}
+
+// exporterType is the YAML discriminator used to decide which concrete
+// exporter configuration type a list entry should be unmarshalled into.
+type exporterType struct {
+	Type string `yaml:"type"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler, dispatching each entry of the
+// exporters list to its concrete type based on its type field.
+func (r *ReverseExporter) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain ReverseExporter
+	aux := struct {
+		plain     `yaml:",inline"`
+		Exporters []yaml.MapSlice `yaml:"exporters"`
+	}{}
+
+	if err := unmarshal(&aux); err != nil {
+		return err
+	}
+	*r = ReverseExporter(aux.plain)
+
+	for _, raw := range aux.Exporters {
+		remarshalled, err := yaml.Marshal(raw)
+		if err != nil {
+			return err
+		}
+
+		var disc exporterType
+		if err := yaml.Unmarshal(remarshalled, &disc); err != nil {
+			return err
+		}
+
+		var exporter Exporter
+		switch disc.Type {
+		case "file":
+			var e FileExporterConfig
+			if err := yaml.Unmarshal(remarshalled, &e); err != nil {
+				return err
+			}
+			exporter = e
+		case "exec":
+			var e ExecExporterConfig
+			if err := yaml.Unmarshal(remarshalled, &e); err != nil {
+				return err
+			}
+			exporter = e
+		case "exec_caching":
+			var e ExecCachingExporterConfig
+			if err := yaml.Unmarshal(remarshalled, &e); err != nil {
+				return err
+			}
+			exporter = e
+		case "http":
+			var e HTTPExporterConfig
+			if err := yaml.Unmarshal(remarshalled, &e); err != nil {
+				return err
+			}
+			exporter = e
+		case "otlp":
+			var e OTLPExporterConfig
+			if err := yaml.Unmarshal(remarshalled, &e); err != nil {
+				return err
+			}
+			exporter = e
+		default:
+			return errors.Errorf("unknown exporter type %q", disc.Type)
+		}
+
+		r.Exporters = append(r.Exporters, exporter)
+	}
+
+	return nil
+}
+
+// LoadFromFile reads and parses a reverse_exporter YAML configuration file.
+func LoadFromFile(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read config file %q", path)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, errors.Wrap(err, "could not parse config file")
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,239 @@
+package metricproxy
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/wrouesnel/reverse_exporter/config"
+)
+
+// ErrBackendCircuitOpen is returned by a circuitBreakerProxy's Scrape when the
+// breaker is open and fast-failing instead of invoking the underlying backend.
+var ErrBackendCircuitOpen = errors.New("backend circuit breaker is open")
+
+// circuitState enumerates the standard closed -> open -> half-open breaker states.
+type circuitState int32
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+const (
+	defaultCircuitWindowSize       = 10
+	defaultCircuitFailureThreshold = 0.5
+	defaultCircuitCooldown         = 30 * time.Second
+	// defaultCircuitMaxCooldownMultiple bounds adaptive backoff when no
+	// max_cooldown is configured, as a multiple of the base cooldown.
+	defaultCircuitMaxCooldownMultiple = 10
+	// circuitBackoffMultiplier is applied to the cooldown each time a
+	// half-open probe fails, so a backend stuck failing keeps being probed
+	// less often instead of on the same fixed interval forever.
+	circuitBackoffMultiplier = 2
+)
+
+// ensure circuitBreakerProxy implements MetricProxy
+var _ MetricProxy = &circuitBreakerProxy{}
+
+// circuitBreakerProxy wraps a MetricProxy with a standard closed/open/half-open
+// circuit breaker. It tracks a sliding window of the last windowSize scrape
+// outcomes; once the failure ratio within that window exceeds
+// failureThreshold, it opens and fails fast with ErrBackendCircuitOpen.
+// Each consecutive failed half-open probe doubles the cooldown up to
+// maxCooldown; a successful probe resets it back to baseCooldown.
+type circuitBreakerProxy struct {
+	name  string
+	proxy MetricProxy
+
+	windowSize       int
+	failureThreshold float64
+	baseCooldown     time.Duration
+	maxCooldown      time.Duration
+
+	mtx             sync.Mutex
+	outcomes        []bool // true = success, oldest first
+	state           circuitState
+	openedAt        time.Time
+	currentCooldown time.Duration
+	probing         bool
+	transitions     int64
+}
+
+// newCircuitBreakerProxy wraps proxy with a circuit breaker configured from cfg,
+// applying sensible defaults for any unset fields.
+func newCircuitBreakerProxy(name string, proxy MetricProxy, cfg *config.CircuitBreakerConfig) *circuitBreakerProxy {
+	windowSize := cfg.WindowSize
+	if windowSize <= 0 {
+		windowSize = defaultCircuitWindowSize
+	}
+
+	failureThreshold := cfg.FailureThreshold
+	if failureThreshold <= 0 {
+		failureThreshold = defaultCircuitFailureThreshold
+	}
+
+	cooldown := time.Duration(cfg.Cooldown)
+	if cooldown <= 0 {
+		cooldown = defaultCircuitCooldown
+	}
+
+	maxCooldown := time.Duration(cfg.MaxCooldown)
+	if maxCooldown <= 0 {
+		maxCooldown = cooldown * defaultCircuitMaxCooldownMultiple
+	}
+
+	return &circuitBreakerProxy{
+		name:             name,
+		proxy:            proxy,
+		windowSize:       windowSize,
+		failureThreshold: failureThreshold,
+		baseCooldown:     cooldown,
+		maxCooldown:      maxCooldown,
+		currentCooldown:  cooldown,
+	}
+}
+
+// Scrape implements MetricProxy.
+func (cb *circuitBreakerProxy) Scrape(ctx context.Context, values url.Values) ([]*dto.MetricFamily, error) {
+	if !cb.allow() {
+		return nil, ErrBackendCircuitOpen
+	}
+
+	mfs, err := cb.proxy.Scrape(ctx, values)
+	cb.recordResult(err == nil)
+	return mfs, err
+}
+
+// allow reports whether a scrape should be attempted, taking and holding the
+// single half-open probe slot if the cooldown has elapsed.
+func (cb *circuitBreakerProxy) allow() bool {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	switch cb.state {
+	case circuitOpen:
+		if time.Since(cb.openedAt) < cb.currentCooldown {
+			return false
+		}
+		cb.setState(circuitHalfOpen)
+		cb.probing = true
+		return true
+	case circuitHalfOpen:
+		if cb.probing {
+			return false
+		}
+		cb.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// recordResult folds a scrape outcome into the breaker's state.
+func (cb *circuitBreakerProxy) recordResult(success bool) {
+	cb.mtx.Lock()
+	defer cb.mtx.Unlock()
+
+	if cb.state == circuitHalfOpen {
+		cb.probing = false
+		if success {
+			cb.outcomes = cb.outcomes[:0]
+			cb.currentCooldown = cb.baseCooldown
+			cb.setState(circuitClosed)
+		} else {
+			cb.openedAt = time.Now()
+			cb.currentCooldown *= circuitBackoffMultiplier
+			if cb.currentCooldown > cb.maxCooldown {
+				cb.currentCooldown = cb.maxCooldown
+			}
+			cb.setState(circuitOpen)
+		}
+		return
+	}
+
+	cb.outcomes = append(cb.outcomes, success)
+	if len(cb.outcomes) > cb.windowSize {
+		cb.outcomes = cb.outcomes[1:]
+	}
+
+	if cb.state == circuitClosed && len(cb.outcomes) >= cb.windowSize && cb.failureRatio() > cb.failureThreshold {
+		cb.openedAt = time.Now()
+		cb.setState(circuitOpen)
+	}
+}
+
+// failureRatio must be called with mtx held.
+func (cb *circuitBreakerProxy) failureRatio() float64 {
+	if len(cb.outcomes) == 0 {
+		return 0
+	}
+
+	failures := 0
+	for _, ok := range cb.outcomes {
+		if !ok {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(cb.outcomes))
+}
+
+// setState must be called with mtx held.
+func (cb *circuitBreakerProxy) setState(s circuitState) {
+	if s == cb.state {
+		return
+	}
+	cb.state = s
+	atomic.AddInt64(&cb.transitions, 1)
+}
+
+// SelfMetrics reports the breaker's current state and cumulative transition
+// count as synthetic metrics, so operators can alert on a flapping backend.
+func (cb *circuitBreakerProxy) SelfMetrics() []*dto.MetricFamily {
+	cb.mtx.Lock()
+	state := cb.state
+	cb.mtx.Unlock()
+
+	return []*dto.MetricFamily{
+		{
+			Name: proto.String("reverse_exporter_circuit_state"),
+			Help: proto.String("Current circuit breaker state for this backend (0=closed, 1=open, 2=half_open)."),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{
+						{Name: proto.String("name"), Value: proto.String(cb.name)},
+					},
+					Gauge: &dto.Gauge{Value: proto.Float64(float64(state))},
+				},
+			},
+		},
+		{
+			Name: proto.String("reverse_exporter_circuit_transitions_total"),
+			Help: proto.String("Total number of circuit breaker state transitions for this backend."),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{
+						{Name: proto.String("name"), Value: proto.String(cb.name)},
+					},
+					Counter: &dto.Counter{Value: proto.Float64(float64(atomic.LoadInt64(&cb.transitions)))},
+				},
+			},
+		},
+	}
+}
+
+// Close forwards to the wrapped proxy if it supports shutting down its own goroutines.
+func (cb *circuitBreakerProxy) Close() error {
+	if closer, ok := cb.proxy.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
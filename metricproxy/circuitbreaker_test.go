@@ -0,0 +1,112 @@
+package metricproxy
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/wrouesnel/reverse_exporter/config"
+)
+
+// fakeProxy is a MetricProxy whose Scrape outcome is controlled by the test.
+type fakeProxy struct {
+	err error
+}
+
+func (f *fakeProxy) Scrape(_ context.Context, _ url.Values) ([]*dto.MetricFamily, error) {
+	return nil, f.err
+}
+
+var errFakeProxyFailure = errFakeProxy("fake backend failure")
+
+type errFakeProxy string
+
+func (e errFakeProxy) Error() string { return string(e) }
+
+func TestCircuitBreakerProxyOpensAfterThresholdBreached(t *testing.T) {
+	backend := &fakeProxy{}
+	cb := newCircuitBreakerProxy("test", backend, &config.CircuitBreakerConfig{
+		WindowSize:       4,
+		FailureThreshold: 0.5,
+		Cooldown:         config.Duration(time.Minute),
+	})
+
+	backend.err = errFakeProxyFailure
+	for i := 0; i < 4; i++ {
+		if _, err := cb.Scrape(context.Background(), nil); err != errFakeProxyFailure {
+			t.Fatalf("scrape %d: expected underlying failure, got %v", i, err)
+		}
+	}
+
+	if cb.state != circuitOpen {
+		t.Fatalf("expected breaker to be open after 4/4 failures, got state %v", cb.state)
+	}
+
+	if _, err := cb.Scrape(context.Background(), nil); err != ErrBackendCircuitOpen {
+		t.Fatalf("expected ErrBackendCircuitOpen while open, got %v", err)
+	}
+}
+
+func TestCircuitBreakerProxyHalfOpenProbeRecovers(t *testing.T) {
+	backend := &fakeProxy{err: errFakeProxyFailure}
+	cb := newCircuitBreakerProxy("test", backend, &config.CircuitBreakerConfig{
+		WindowSize:       2,
+		FailureThreshold: 0.5,
+		Cooldown:         config.Duration(time.Millisecond),
+	})
+
+	for i := 0; i < 2; i++ {
+		_, _ = cb.Scrape(context.Background(), nil)
+	}
+	if cb.state != circuitOpen {
+		t.Fatalf("expected breaker to be open, got state %v", cb.state)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+
+	backend.err = nil
+	if _, err := cb.Scrape(context.Background(), nil); err != nil {
+		t.Fatalf("expected half-open probe to succeed, got %v", err)
+	}
+	if cb.state != circuitClosed {
+		t.Fatalf("expected breaker to close after a successful probe, got state %v", cb.state)
+	}
+}
+
+func TestCircuitBreakerProxyCooldownBacksOffOnRepeatedTrips(t *testing.T) {
+	backend := &fakeProxy{err: errFakeProxyFailure}
+	cb := newCircuitBreakerProxy("test", backend, &config.CircuitBreakerConfig{
+		WindowSize:       1,
+		FailureThreshold: 0.5,
+		Cooldown:         config.Duration(time.Millisecond),
+		MaxCooldown:      config.Duration(10 * time.Millisecond),
+	})
+
+	// Open the breaker.
+	_, _ = cb.Scrape(context.Background(), nil)
+	if cb.currentCooldown != time.Millisecond {
+		t.Fatalf("expected initial cooldown to be the base cooldown, got %v", cb.currentCooldown)
+	}
+
+	// Fail the half-open probe twice in a row; the cooldown should grow each time, capped at MaxCooldown.
+	for i, want := range []time.Duration{2 * time.Millisecond, 4 * time.Millisecond} {
+		time.Sleep(cb.currentCooldown + time.Millisecond)
+		if _, err := cb.Scrape(context.Background(), nil); err != errFakeProxyFailure {
+			t.Fatalf("probe %d: expected underlying failure, got %v", i, err)
+		}
+		if cb.currentCooldown != want {
+			t.Fatalf("probe %d: expected cooldown %v, got %v", i, want, cb.currentCooldown)
+		}
+	}
+
+	// Keep tripping until the cooldown saturates at MaxCooldown.
+	for i := 0; i < 5; i++ {
+		time.Sleep(cb.currentCooldown + time.Millisecond)
+		_, _ = cb.Scrape(context.Background(), nil)
+	}
+	if cb.currentCooldown != 10*time.Millisecond {
+		t.Fatalf("expected cooldown to saturate at MaxCooldown, got %v", cb.currentCooldown)
+	}
+}
@@ -0,0 +1,28 @@
+package metricproxy
+
+import (
+	"io"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// decodeMetrics decodes a stream of metric families in the given format, reading
+// until the underlying reader is exhausted.
+func decodeMetrics(r io.Reader, format expfmt.Format) ([]*dto.MetricFamily, error) {
+	decoder := expfmt.NewDecoder(r, format)
+
+	mfs := make([]*dto.MetricFamily, 0)
+	for {
+		mf := &dto.MetricFamily{}
+		if err := decoder.Decode(mf); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		mfs = append(mfs, mf)
+	}
+
+	return mfs, nil
+}
@@ -0,0 +1,157 @@
+package metricproxy
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// namedBackend pairs a MetricProxy with the exporter name it was configured
+// under and the per-backend scrape timeout, so a slow or dead backend can be
+// bounded and reported on individually instead of stalling the whole endpoint.
+type namedBackend struct {
+	name    string
+	timeout time.Duration
+	proxy   MetricProxy
+}
+
+// ReverseProxyEndpoint implements http.Handler, merging the scrapes of all of its
+// configured backends into a single Prometheus-format response.
+type ReverseProxyEndpoint struct {
+	metricPath string
+	backends   []namedBackend
+	handler    http.HandlerFunc
+}
+
+// ServeHTTP implements http.Handler.
+func (rpe *ReverseProxyEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rpe.handler(w, r)
+}
+
+// Close releases this endpoint's reference to each of its backends, stopping
+// their goroutines once no other endpoint still references them.
+func (rpe *ReverseProxyEndpoint) Close() error {
+	var firstErr error
+	for _, backend := range rpe.backends {
+		releaser, ok := backend.proxy.(interface{ Release() error })
+		if !ok {
+			continue
+		}
+		if err := releaser.Release(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// serveMetricsHTTP scrapes all configured backends concurrently, each bounded
+// by its own timeout, and writes the merged result back to the requester. A
+// backend which errors or times out does not fail the response: it is instead
+// reported via synthetic reverse_exporter_backend_up/scrape_duration_seconds
+// metrics, the same signal Prometheus itself exposes for direct targets.
+func (rpe *ReverseProxyEndpoint) serveMetricsHTTP(w http.ResponseWriter, r *http.Request) {
+	log := zap.L().With(zap.String("path", rpe.metricPath)) // nolint: vetshadow
+
+	results := make([][]*dto.MetricFamily, len(rpe.backends))
+
+	grp, _ := errgroup.WithContext(r.Context())
+	for i, backend := range rpe.backends {
+		i, backend := i, backend // nolint: scopelint
+		grp.Go(func() error {
+			results[i] = scrapeBackend(r.Context(), backend, r.URL.Query(), log)
+			// Backend failures are reported as synthetic metrics rather than
+			// propagated, so the group never actually fails.
+			return nil
+		})
+	}
+	_ = grp.Wait()
+
+	// Honor whatever format the scraping Prometheus (or curl) asked for via its
+	// Accept header, instead of always emitting the plain text format.
+	responseFormat := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+
+	w.Header().Set("Content-Type", string(responseFormat))
+	enc := expfmt.NewEncoder(w, responseFormat)
+	for _, mfs := range results {
+		for _, mf := range mfs {
+			if err := enc.Encode(mf); err != nil {
+				log.Error("Error encoding metric family to response", zap.Error(err))
+			}
+		}
+	}
+}
+
+// scrapeBackend scrapes a single backend under its configured timeout and
+// appends the up/scrape-duration self-metrics to whatever it returned.
+func scrapeBackend(
+	ctx context.Context, backend namedBackend, values map[string][]string, log *zap.Logger,
+) []*dto.MetricFamily {
+	scrapeCtx := ctx
+	if backend.timeout > 0 {
+		var cancel context.CancelFunc
+		scrapeCtx, cancel = context.WithTimeout(ctx, backend.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	mfs, err := backend.proxy.Scrape(scrapeCtx, values)
+	duration := time.Since(start)
+
+	if err != nil {
+		log.Error("Backend scrape failed", zap.String("name", backend.name), zap.Error(err))
+		mfs = nil
+	}
+
+	mfs = append(mfs, backendUpMetric(backend.name, err == nil), backendScrapeDurationMetric(backend.name, duration))
+
+	if provider, ok := backend.proxy.(interface{ SelfMetrics() []*dto.MetricFamily }); ok {
+		mfs = append(mfs, provider.SelfMetrics()...)
+	}
+
+	return mfs
+}
+
+// backendUpMetric reports whether the most recent scrape of a backend succeeded.
+func backendUpMetric(name string, up bool) *dto.MetricFamily {
+	value := 0.0
+	if up {
+		value = 1.0
+	}
+
+	return &dto.MetricFamily{
+		Name: proto.String("reverse_exporter_backend_up"),
+		Help: proto.String("Whether the last scrape of this backend succeeded (1) or failed (0)."),
+		Type: dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{
+			{
+				Label: []*dto.LabelPair{
+					{Name: proto.String("name"), Value: proto.String(name)},
+				},
+				Gauge: &dto.Gauge{Value: proto.Float64(value)},
+			},
+		},
+	}
+}
+
+// backendScrapeDurationMetric reports how long the most recent scrape of a backend took.
+func backendScrapeDurationMetric(name string, d time.Duration) *dto.MetricFamily {
+	return &dto.MetricFamily{
+		Name: proto.String("reverse_exporter_backend_scrape_duration_seconds"),
+		Help: proto.String("Duration in seconds of the last scrape of this backend."),
+		Type: dto.MetricType_GAUGE.Enum(),
+		Metric: []*dto.Metric{
+			{
+				Label: []*dto.LabelPair{
+					{Name: proto.String("name"), Value: proto.String(name)},
+				},
+				Gauge: &dto.Gauge{Value: proto.Float64(d.Seconds())},
+			},
+		},
+	}
+}
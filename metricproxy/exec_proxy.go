@@ -1,4 +1,4 @@
-package metricProxy
+package metricproxy
 
 import (
 	"context"
@@ -35,11 +35,16 @@ type execProxyScrapeResult struct {
 type execProxy struct {
 	commandPath string
 	arguments   []string
+	// format is the exposition format the script's stdout is decoded as.
+	format expfmt.Format
 	// waitingScrapes is a list of channels which are currently waiting for the results of a command executions
 	waitingScrapes map[chan<- *execProxyScrapeResult]struct{}
 	waitingMtx     *sync.Mutex
 	// Incoming scrapes send to this channel to request results
 	execReqCh chan<- struct{}
+	// doneCh is closed by Close to stop the execer goroutine once the proxy is no longer referenced.
+	doneCh    chan struct{}
+	closeOnce sync.Once
 	log       log.Logger
 }
 
@@ -50,9 +55,11 @@ func newExecProxy(config *config.ExecExporterConfig) *execProxy {
 	newProxy := execProxy{
 		commandPath:    config.Command,
 		arguments:      config.Args,
+		format:         parseScrapeFormat(config.Format),
 		waitingScrapes: make(map[chan<- *execProxyScrapeResult]struct{}),
 		waitingMtx:     &sync.Mutex{},
 		execReqCh:      execReqCh,
+		doneCh:         make(chan struct{}),
 		log:            log.Base(),
 	}
 
@@ -61,6 +68,14 @@ func newExecProxy(config *config.ExecExporterConfig) *execProxy {
 	return &newProxy
 }
 
+// Close stops the execer goroutine backing this proxy. It is safe to call more than once.
+func (ep *execProxy) Close() error {
+	ep.closeOnce.Do(func() {
+		close(ep.doneCh)
+	})
+	return nil
+}
+
 // doExec handles the actual application execution.
 func (ep *execProxy) doExec() *execProxyScrapeResult {
 	// allocate a new result struct now
@@ -94,7 +109,7 @@ func (ep *execProxy) doExec() *execProxyScrapeResult {
 	//	continue
 	//}
 
-	mfs, derr := decodeMetrics(outRdr, expfmt.FmtText)
+	mfs, derr := decodeMetrics(outRdr, ep.format)
 	// Hard kill the script once metric decoding finishes. It's the only way to be sure.
 	// Maybe sigterm with a timeout?
 	if err := cmd.Process.Kill(); err != nil {
@@ -116,7 +131,12 @@ func (ep *execProxy) doExec() *execProxyScrapeResult {
 func (ep *execProxy) execer(reqCh <-chan struct{}) {
 	ep.log.Debugln("ExecProxy started")
 	for {
-		<-reqCh
+		select {
+		case <-ep.doneCh:
+			ep.log.Debugln("ExecProxy stopped")
+			return
+		case <-reqCh:
+		}
 		// Got a request. Check there is non-zero waiting requestors (i.e. maybe this was satisfied by the
 		// loop gone-by
 		ep.waitingMtx.Lock()
@@ -178,12 +198,18 @@ type execCachingProxy struct {
 	commandPath  string
 	arguments    []string
 	execInterval time.Duration
+	// format is the exposition format the script's stdout is decoded as.
+	format expfmt.Format
 
 	lastExec      time.Time
 	lastResult    []*dto.MetricFamily
 	resultReadyCh <-chan struct{}
 	lastResultMtx *sync.RWMutex
 
+	// doneCh is closed by Close to stop the execer goroutine once the proxy is no longer referenced.
+	doneCh    chan struct{}
+	closeOnce sync.Once
+
 	log log.Logger
 }
 
@@ -195,11 +221,14 @@ func newExecCachingProxy(config *config.ExecCachingExporterConfig) *execCachingP
 		commandPath:  config.Command,
 		arguments:    config.Args,
 		execInterval: time.Duration(config.ExecInterval),
+		format:       parseScrapeFormat(config.Format),
 
 		lastResult:    make([]*dto.MetricFamily, 0),
 		resultReadyCh: rdyCh,
 		lastResultMtx: &sync.RWMutex{},
 
+		doneCh: make(chan struct{}),
+
 		log: log.Base(),
 	}
 
@@ -208,6 +237,14 @@ func newExecCachingProxy(config *config.ExecCachingExporterConfig) *execCachingP
 	return &newProxy
 }
 
+// Close stops the execer goroutine backing this proxy. It is safe to call more than once.
+func (ecp *execCachingProxy) Close() error {
+	ecp.closeOnce.Do(func() {
+		close(ecp.doneCh)
+	})
+	return nil
+}
+
 func (ecp *execCachingProxy) execer(rdyCh chan<- struct{}) {
 	ecp.log.Debugln("ExecCachingProxy started")
 
@@ -215,7 +252,12 @@ func (ecp *execCachingProxy) execer(rdyCh chan<- struct{}) {
 		nextExec := ecp.lastExec.Add(ecp.execInterval)
 		ecp.log.With("next_exec", nextExec.String()).
 			Debugln("Waiting for next interval")
-		<-time.After(time.Until(nextExec))
+		select {
+		case <-ecp.doneCh:
+			ecp.log.Debugln("ExecCachingProxy stopped")
+			return
+		case <-time.After(time.Until(nextExec)):
+		}
 		ecp.log.Debugln("Executing metric script on timeout")
 
 		ecp.lastExec = time.Now()
@@ -239,7 +281,7 @@ func (ecp *execCachingProxy) execer(rdyCh chan<- struct{}) {
 		//	continue
 		//}
 
-		mfs, derr := decodeMetrics(outRdr, expfmt.FmtText)
+		mfs, derr := decodeMetrics(outRdr, ecp.format)
 		// Hard kill the script once metric decoding finishes. It's the only way to be sure.
 		// Maybe sigterm with a timeout?
 		if err := cmd.Process.Kill(); err != nil {
@@ -0,0 +1,39 @@
+package metricproxy
+
+import (
+	"context"
+	"net/url"
+	"os"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/wrouesnel/reverse_exporter/config"
+
+	"github.com/pkg/errors"
+)
+
+// ensure fileProxy implements MetricProxy
+var _ MetricProxy = &fileProxy{}
+
+// fileProxy implements a metric proxy which serves the contents of a static file on disk.
+type fileProxy struct {
+	path string
+}
+
+// newFileProxy initializes a new fileProxy from the given configuration.
+func newFileProxy(config *config.FileExporterConfig) *fileProxy {
+	return &fileProxy{
+		path: config.Path,
+	}
+}
+
+// Scrape implements MetricProxy.
+func (fp *fileProxy) Scrape(ctx context.Context, values url.Values) ([]*dto.MetricFamily, error) {
+	f, err := os.Open(fp.path) // nolint: gosec
+	if err != nil {
+		return nil, errors.Wrap(ErrFileProxyScrapeError, err.Error())
+	}
+	defer f.Close() // nolint: errcheck
+
+	return decodeMetrics(f, expfmt.FmtText)
+}
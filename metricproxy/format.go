@@ -0,0 +1,20 @@
+package metricproxy
+
+import "github.com/prometheus/common/expfmt"
+
+// parseScrapeFormat maps the `format:` config option of an exec-based exporter
+// onto the expfmt.Format its stdout should be decoded as. An empty or
+// unrecognised value falls back to the plain text exposition format, which
+// matches the previous hardcoded behaviour.
+func parseScrapeFormat(format string) expfmt.Format {
+	switch format {
+	case "openmetrics":
+		return expfmt.FmtOpenMetrics
+	case "protobuf":
+		return expfmt.FmtProtoDelim
+	case "text", "":
+		return expfmt.FmtText
+	default:
+		return expfmt.FmtText
+	}
+}
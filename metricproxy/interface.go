@@ -16,6 +16,9 @@ import (
 	dto "github.com/prometheus/client_model/go"
 )
 
+// authRealm is the HTTP basic auth realm presented to clients of endpoints with auth_type: basic.
+const authRealm = "reverse_exporter"
+
 // nolint: golint
 var (
 	ErrNameFieldOverrideAttempted = errors.New("cannot override name field with additional labels")
@@ -37,7 +40,7 @@ func NewMetricReverseProxy(exporter config.ReverseExporter) (http.Handler, error
 	// Initialize a basic reverse proxy
 	backend := &ReverseProxyEndpoint{
 		metricPath: exporter.Path,
-		backends:   make([]MetricProxy, 0),
+		backends:   make([]namedBackend, 0),
 	}
 	backend.handler = backend.serveMetricsHTTP
 
@@ -47,7 +50,7 @@ func NewMetricReverseProxy(exporter config.ReverseExporter) (http.Handler, error
 	for _, exporter := range exporter.Exporters {
 		var newExporter MetricProxy
 
-		baseExporter := exporter.(config.BaseExporter).GetBaseExporter()
+		baseExporter := exporter.GetBaseExporter()
 		log := log.With(zap.String("name", baseExporter.Name)) // nolint: vetshadow
 
 		switch e := exporter.(type) {
@@ -62,16 +65,41 @@ func NewMetricReverseProxy(exporter config.ReverseExporter) (http.Handler, error
 			newExporter = newExecCachingProxy(&e)
 		case config.HTTPExporterConfig:
 			log.Debug("Adding new http exporter proxy")
-			newExporter = &netProxy{
+			np := &netProxy{
 				address:            e.Address,
 				deadline:           time.Duration(e.Timeout),
 				forwardQueryParams: e.ForwardURLParams,
 			}
+
+			if e.TLS != nil {
+				client, terr := newBackendTLSClient(e.TLS)
+				if terr != nil {
+					log.Error("Could not configure backend TLS client certificate", zap.Error(terr))
+					return nil, terr
+				}
+				np.client = client
+			}
+
+			newExporter = np
+		case config.OTLPExporterConfig:
+			log.Debug("Adding new otlp exporter proxy")
+			op, oerr := newOTLPProxy(&e)
+			if oerr != nil {
+				log.Error("Could not configure otlp backend", zap.Error(oerr))
+				return nil, oerr
+			}
+			newExporter = op
 		default:
 			log.Error("Unknown proxy configuration item found", zap.Reflect("item", e))
 			return nil, ErrUnknownExporterType
 		}
 
+		// Wrap with a circuit breaker if configured, so a consistently failing
+		// backend is failed fast instead of being retried on every scrape.
+		if baseExporter.CircuitBreaker != nil {
+			newExporter = newCircuitBreakerProxy(baseExporter.Name, newExporter, baseExporter.CircuitBreaker)
+		}
+
 		// Got exporter, now add a rewrite proxy in front of it
 		labels := make(model.LabelSet)
 
@@ -92,20 +120,33 @@ func NewMetricReverseProxy(exporter config.ReverseExporter) (http.Handler, error
 
 		// Set the additional labels.
 		for k, v := range baseExporter.Labels {
-			if k == reverseProxyNameLabel {
+			if model.LabelName(k) == reverseProxyNameLabel {
 				return nil, ErrNameFieldOverrideAttempted
 			}
 			labels[model.LabelName(k)] = model.LabelValue(v)
 		}
 
+		// Compile this backend's metric_relabel_configs once up-front so they
+		// can be applied cheaply on every subsequent scrape.
+		relabelConfigs, rerr := compileRelabelConfigs(baseExporter.MetricRelabelConfigs)
+		if rerr != nil {
+			log.Error("Could not compile metric_relabel_configs", zap.Error(rerr))
+			return nil, rerr
+		}
+
 		// Configure the rewriting proxy shim.
 		rewriteProxy := &rewriteProxy{
-			proxy:  newExporter,
-			labels: labels,
+			proxy:          newExporter,
+			labels:         labels,
+			relabelConfigs: relabelConfigs,
 		}
 
 		// Add the new backend to the endpoint
-		backend.backends = append(backend.backends, rewriteProxy)
+		backend.backends = append(backend.backends, namedBackend{
+			name:    baseExporter.Name,
+			timeout: time.Duration(baseExporter.ScrapeTimeout),
+			proxy:   rewriteProxy,
+		})
 	}
 
 	// Process the auth configuration
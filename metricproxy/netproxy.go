@@ -0,0 +1,57 @@
+package metricproxy
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// ensure netProxy implements MetricProxy
+var _ MetricProxy = &netProxy{}
+
+// netProxy implements a metric proxy which scrapes another HTTP exporter.
+type netProxy struct {
+	address            string
+	deadline           time.Duration
+	forwardQueryParams bool
+	// client is used to perform the scrape request. It is nil unless the
+	// exporter was configured with a TLS client certificate, in which case it
+	// presents that certificate to the backend instead of using http.DefaultClient.
+	client *http.Client
+}
+
+// Scrape implements MetricProxy.
+func (np *netProxy) Scrape(ctx context.Context, values url.Values) ([]*dto.MetricFamily, error) {
+	if np.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, np.deadline)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, np.address, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	if np.forwardQueryParams {
+		req.URL.RawQuery = values.Encode()
+	}
+
+	client := np.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	return decodeMetrics(resp.Body, expfmt.FmtText)
+}
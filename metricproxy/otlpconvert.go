@@ -0,0 +1,309 @@
+package metricproxy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// defaultExponentialHistogramBounds are the fixed bucket boundaries an OTLP
+// exponential histogram's bucket counts are folded into, since Prometheus's
+// histogram type only supports boundaries declared up-front rather than the
+// base-2 exponential scale/offset scheme OTLP uses natively.
+var defaultExponentialHistogramBounds = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100,
+}
+
+// convertOTLPToPromFamilies converts a set of OTLP ResourceMetrics into
+// Prometheus metric families, lifting resource attributes to labels and
+// applying OTLP's name-to-Prometheus-name conventions.
+func convertOTLPToPromFamilies(resourceMetrics []*metricspb.ResourceMetrics) []*dto.MetricFamily {
+	out := make([]*dto.MetricFamily, 0, len(resourceMetrics))
+
+	for _, rm := range resourceMetrics {
+		resourceLabels := attributesToLabelPairs(rm.GetResource().GetAttributes())
+
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				if mf := convertOTLPMetric(m, resourceLabels); mf != nil {
+					out = append(out, mf)
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+// otlpMetricName maps an OTLP metric name/unit onto the Prometheus naming
+// convention: dots become underscores and a non-trivial unit is appended as a suffix.
+func otlpMetricName(m *metricspb.Metric) string {
+	name := strings.ReplaceAll(m.GetName(), ".", "_")
+
+	unit := strings.ReplaceAll(m.GetUnit(), ".", "_")
+	if unit != "" && unit != "1" && !strings.HasSuffix(name, "_"+unit) {
+		name += "_" + unit
+	}
+
+	return name
+}
+
+// convertOTLPMetric converts a single OTLP metric into a Prometheus metric
+// family, or returns nil if its data type is not one reverse_exporter understands.
+func convertOTLPMetric(m *metricspb.Metric, resourceLabels []*dto.LabelPair) *dto.MetricFamily {
+	name := otlpMetricName(m)
+
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		return &dto.MetricFamily{
+			Name: proto.String(name),
+			Help: proto.String(m.GetDescription()),
+			Type: dto.MetricType_GAUGE.Enum(),
+			Metric: numberDataPointsToMetrics(data.Gauge.GetDataPoints(), resourceLabels,
+				func(v float64) *dto.Metric {
+					return &dto.Metric{Gauge: &dto.Gauge{Value: proto.Float64(v)}}
+				}),
+		}
+	case *metricspb.Metric_Sum:
+		return &dto.MetricFamily{
+			Name: proto.String(name),
+			Help: proto.String(m.GetDescription()),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: numberDataPointsToMetrics(data.Sum.GetDataPoints(), resourceLabels,
+				func(v float64) *dto.Metric {
+					return &dto.Metric{Counter: &dto.Counter{Value: proto.Float64(v)}}
+				}),
+		}
+	case *metricspb.Metric_Histogram:
+		return &dto.MetricFamily{
+			Name:   proto.String(name),
+			Help:   proto.String(m.GetDescription()),
+			Type:   dto.MetricType_HISTOGRAM.Enum(),
+			Metric: histogramDataPointsToMetrics(data.Histogram.GetDataPoints(), resourceLabels),
+		}
+	case *metricspb.Metric_ExponentialHistogram:
+		return &dto.MetricFamily{
+			Name:   proto.String(name),
+			Help:   proto.String(m.GetDescription()),
+			Type:   dto.MetricType_HISTOGRAM.Enum(),
+			Metric: exponentialHistogramDataPointsToMetrics(data.ExponentialHistogram.GetDataPoints(), resourceLabels),
+		}
+	default:
+		return nil
+	}
+}
+
+// attributesToLabelPairs converts OTLP key/value attributes into dto label pairs.
+func attributesToLabelPairs(attrs []*commonpb.KeyValue) []*dto.LabelPair {
+	labels := make([]*dto.LabelPair, 0, len(attrs))
+	for _, a := range attrs {
+		labels = append(labels, &dto.LabelPair{
+			Name:  proto.String(strings.ReplaceAll(a.GetKey(), ".", "_")),
+			Value: proto.String(attributeValueToString(a.GetValue())),
+		})
+	}
+	return labels
+}
+
+// attributeValueToString stringifies an OTLP attribute value of any kind, so
+// a numeric/boolean attribute (e.g. a k8s.pod.uid or service.instance.id)
+// becomes a usable label instead of silently collapsing to "".
+func attributeValueToString(v *commonpb.AnyValue) string {
+	switch val := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return val.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return fmt.Sprintf("%t", val.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return fmt.Sprintf("%d", val.IntValue)
+	case *commonpb.AnyValue_DoubleValue:
+		return fmt.Sprintf("%g", val.DoubleValue)
+	case *commonpb.AnyValue_ArrayValue:
+		parts := make([]string, 0, len(val.ArrayValue.GetValues()))
+		for _, elem := range val.ArrayValue.GetValues() {
+			parts = append(parts, attributeValueToString(elem))
+		}
+		return strings.Join(parts, ",")
+	case *commonpb.AnyValue_KvlistValue:
+		return attributeValueToString(&commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{
+			ArrayValue: &commonpb.ArrayValue{Values: kvlistToAnyValues(val.KvlistValue.GetValues())},
+		}})
+	case *commonpb.AnyValue_BytesValue:
+		return string(val.BytesValue)
+	default:
+		return ""
+	}
+}
+
+// kvlistToAnyValues flattens a KeyValueList's entries into "key=value" scalars
+// for attributeValueToString's array-join fallback.
+func kvlistToAnyValues(kvs []*commonpb.KeyValue) []*commonpb.AnyValue {
+	out := make([]*commonpb.AnyValue, 0, len(kvs))
+	for _, kv := range kvs {
+		out = append(out, &commonpb.AnyValue{
+			Value: &commonpb.AnyValue_StringValue{
+				StringValue: fmt.Sprintf("%s=%s", kv.GetKey(), attributeValueToString(kv.GetValue())),
+			},
+		})
+	}
+	return out
+}
+
+func numberDataPointsToMetrics(
+	dps []*metricspb.NumberDataPoint, resourceLabels []*dto.LabelPair, build func(float64) *dto.Metric,
+) []*dto.Metric {
+	metrics := make([]*dto.Metric, 0, len(dps))
+
+	for _, dp := range dps {
+		var value float64
+		switch v := dp.GetValue().(type) {
+		case *metricspb.NumberDataPoint_AsDouble:
+			value = v.AsDouble
+		case *metricspb.NumberDataPoint_AsInt:
+			value = float64(v.AsInt)
+		}
+
+		metric := build(value)
+		metric.Label = mergeLabels(resourceLabels, attributesToLabelPairs(dp.GetAttributes()))
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+func histogramDataPointsToMetrics(dps []*metricspb.HistogramDataPoint, resourceLabels []*dto.LabelPair) []*dto.Metric {
+	metrics := make([]*dto.Metric, 0, len(dps))
+
+	for _, dp := range dps {
+		bounds := dp.GetExplicitBounds()
+		counts := dp.GetBucketCounts()
+
+		buckets := make([]*dto.Bucket, 0, len(bounds))
+		var cumulative uint64
+		for i, bound := range bounds {
+			if i < len(counts) {
+				cumulative += counts[i]
+			}
+			buckets = append(buckets, &dto.Bucket{
+				UpperBound:      proto.Float64(bound),
+				CumulativeCount: proto.Uint64(cumulative),
+			})
+		}
+		if len(counts) > len(bounds) {
+			cumulative += counts[len(counts)-1]
+		}
+
+		metrics = append(metrics, &dto.Metric{
+			Label: mergeLabels(resourceLabels, attributesToLabelPairs(dp.GetAttributes())),
+			Histogram: &dto.Histogram{
+				SampleCount: proto.Uint64(cumulative),
+				SampleSum:   proto.Float64(dp.GetSum()),
+				Bucket:      buckets,
+			},
+		})
+	}
+
+	return metrics
+}
+
+// expHistogramBase returns the per-bucket growth factor for an OTLP
+// exponential histogram recorded at the given scale: base = 2^(2^-scale), so
+// native bucket index i covers the range (base^i, base^(i+1)].
+func expHistogramBase(scale int32) float64 {
+	return math.Pow(2, math.Pow(2, -float64(scale)))
+}
+
+// addFixedBucketCounts folds a set of native exponential-histogram buckets
+// into dst, which is indexed the same as defaultExponentialHistogramBounds.
+// Each native bucket's count is added to the smallest fixed boundary at
+// least as large as the native bucket's real upper edge (derived from
+// offset/index and base), so a count only ever lands in a fixed bucket it
+// actually belongs under. A native bucket whose upper edge exceeds every
+// fixed boundary is left out of the explicit buckets entirely - same as
+// Prometheus's own implicit +Inf bucket, which is always the metric's total SampleCount.
+func addFixedBucketCounts(counts []uint64, offset int32, base float64, dst []uint64) {
+	for i, c := range counts {
+		if c == 0 {
+			continue
+		}
+
+		upperEdge := math.Pow(base, float64(offset)+float64(i)+1)
+		idx := sort.SearchFloat64s(defaultExponentialHistogramBounds, upperEdge)
+		if idx < len(dst) {
+			dst[idx] += c
+		}
+	}
+}
+
+// exponentialHistogramDataPointsToMetrics converts an OTLP exponential
+// histogram into a fixed-boundary Prometheus histogram by deriving each
+// native bucket's real edges from its scale/offset/index and accumulating it
+// into defaultExponentialHistogramBounds, rather than spreading counts
+// evenly across the ladder.
+func exponentialHistogramDataPointsToMetrics(
+	dps []*metricspb.ExponentialHistogramDataPoint, resourceLabels []*dto.LabelPair,
+) []*dto.Metric {
+	metrics := make([]*dto.Metric, 0, len(dps))
+
+	for _, dp := range dps {
+		base := expHistogramBase(dp.GetScale())
+
+		fixedCounts := make([]uint64, len(defaultExponentialHistogramBounds))
+
+		positive := dp.GetPositive()
+		addFixedBucketCounts(positive.GetBucketCounts(), positive.GetOffset(), base, fixedCounts)
+
+		// Prometheus's fixed bucket ladder only covers positive values; fold
+		// the zero bucket and any negative-value buckets into the smallest
+		// boundary so they still contribute to SampleCount/SampleSum without
+		// corrupting a positive bucket's count.
+		fixedCounts[0] += dp.GetZeroCount()
+		for _, c := range dp.GetNegative().GetBucketCounts() {
+			fixedCounts[0] += c
+		}
+
+		var cumulative uint64
+		buckets := make([]*dto.Bucket, 0, len(defaultExponentialHistogramBounds))
+		for i, bound := range defaultExponentialHistogramBounds {
+			cumulative += fixedCounts[i]
+			buckets = append(buckets, &dto.Bucket{
+				UpperBound:      proto.Float64(bound),
+				CumulativeCount: proto.Uint64(cumulative),
+			})
+		}
+
+		total := dp.GetZeroCount()
+		for _, c := range positive.GetBucketCounts() {
+			total += c
+		}
+		for _, c := range dp.GetNegative().GetBucketCounts() {
+			total += c
+		}
+
+		metrics = append(metrics, &dto.Metric{
+			Label: mergeLabels(resourceLabels, attributesToLabelPairs(dp.GetAttributes())),
+			Histogram: &dto.Histogram{
+				SampleCount: proto.Uint64(total),
+				SampleSum:   proto.Float64(dp.GetSum()),
+				Bucket:      buckets,
+			},
+		})
+	}
+
+	return metrics
+}
+
+// mergeLabels concatenates label sets, e.g. resource-level and data-point-level attributes.
+func mergeLabels(sets ...[]*dto.LabelPair) []*dto.LabelPair {
+	merged := make([]*dto.LabelPair, 0)
+	for _, set := range sets {
+		merged = append(merged, set...)
+	}
+	return merged
+}
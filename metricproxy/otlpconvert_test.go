@@ -0,0 +1,91 @@
+package metricproxy
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+func TestExponentialHistogramDataPointsToMetricsBucketsByRealEdges(t *testing.T) {
+	// scale=0 -> base=2, so native bucket index i covers (2^i, 2^(i+1)].
+	// offset=0, bucket_counts[0] covers (1, 2] and should land in the
+	// smallest fixed boundary >= 2, i.e. 2.5 - not spread across every bucket.
+	dp := &metricspb.ExponentialHistogramDataPoint{
+		Scale: 0,
+		Sum:   proto.Float64(30),
+		Positive: &metricspb.ExponentialHistogramDataPoint_Buckets{
+			Offset:       0,
+			BucketCounts: []uint64{5},
+		},
+	}
+
+	metrics := exponentialHistogramDataPointsToMetrics([]*metricspb.ExponentialHistogramDataPoint{dp}, nil)
+	if len(metrics) != 1 {
+		t.Fatalf("expected 1 metric, got %d", len(metrics))
+	}
+
+	// Cumulative counts are monotonic: every boundary >= 2.5 (the smallest
+	// boundary that covers the native bucket's real (1, 2] upper edge) should
+	// carry all 5 samples, and every boundary below it should carry none.
+	buckets := metrics[0].GetHistogram().GetBucket()
+	for _, b := range buckets {
+		want := uint64(0)
+		if b.GetUpperBound() >= 2.5 {
+			want = 5
+		}
+		if b.GetCumulativeCount() != want {
+			t.Errorf("le=%v bucket: expected cumulative count %d, got %d", b.GetUpperBound(), want, b.GetCumulativeCount())
+		}
+	}
+
+	if got := metrics[0].GetHistogram().GetSampleCount(); got != 5 {
+		t.Errorf("expected SampleCount 5, got %d", got)
+	}
+	if got := metrics[0].GetHistogram().GetSampleSum(); got != 30 {
+		t.Errorf("expected SampleSum 30, got %v", got)
+	}
+}
+
+func TestExponentialHistogramDataPointsToMetricsFoldsZeroAndNegativeIntoSmallestBucket(t *testing.T) {
+	dp := &metricspb.ExponentialHistogramDataPoint{
+		Scale:     0,
+		ZeroCount: 2,
+		Negative: &metricspb.ExponentialHistogramDataPoint_Buckets{
+			Offset:       0,
+			BucketCounts: []uint64{3},
+		},
+	}
+
+	metrics := exponentialHistogramDataPointsToMetrics([]*metricspb.ExponentialHistogramDataPoint{dp}, nil)
+
+	buckets := metrics[0].GetHistogram().GetBucket()
+	if buckets[0].GetCumulativeCount() != 5 {
+		t.Errorf("expected zero+negative counts folded into the smallest bucket, got %d", buckets[0].GetCumulativeCount())
+	}
+	if got := metrics[0].GetHistogram().GetSampleCount(); got != 5 {
+		t.Errorf("expected SampleCount 5, got %d", got)
+	}
+}
+
+func TestAttributeValueToStringStringifiesNonStringKinds(t *testing.T) {
+	cases := []struct {
+		name string
+		in   *commonpb.AnyValue
+		want string
+	}{
+		{"string", &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "foo"}}, "foo"},
+		{"bool", &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}, "true"},
+		{"int", &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 42}}, "42"},
+		{"double", &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: 1.5}}, "1.5"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := attributeValueToString(c.in); got != c.want {
+				t.Errorf("attributeValueToString(%s) = %q, want %q", c.name, got, c.want)
+			}
+		})
+	}
+}
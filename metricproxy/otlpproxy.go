@@ -0,0 +1,131 @@
+package metricproxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	colmetricpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+
+	"github.com/wrouesnel/reverse_exporter/config"
+)
+
+// ensure otlpProxy implements MetricProxy
+var _ MetricProxy = &otlpProxy{}
+
+// otlpProxy scrapes an OTLP/HTTP metrics producer and converts the response
+// into Prometheus metric families, so a reverse_exporter endpoint can sit in
+// front of an application which only speaks OTLP.
+type otlpProxy struct {
+	address            string
+	method             string
+	deadline           time.Duration
+	gzip               bool
+	forwardQueryParams bool
+	client             *http.Client
+}
+
+// newOTLPProxy initializes a new otlpProxy from the given configuration.
+func newOTLPProxy(cfg *config.OTLPExporterConfig) (*otlpProxy, error) {
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	op := &otlpProxy{
+		address:            cfg.Address,
+		method:             method,
+		deadline:           time.Duration(cfg.Timeout),
+		gzip:               cfg.Gzip,
+		forwardQueryParams: cfg.ForwardURLParams,
+	}
+
+	if cfg.TLS != nil {
+		client, err := newBackendTLSClient(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		op.client = client
+	}
+
+	return op, nil
+}
+
+// Scrape implements MetricProxy.
+func (op *otlpProxy) Scrape(ctx context.Context, values url.Values) ([]*dto.MetricFamily, error) {
+	if op.deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, op.deadline)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if op.method == http.MethodPost {
+		reqBytes, err := proto.Marshal(&colmetricpb.ExportMetricsServiceRequest{})
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(reqBytes)
+	}
+
+	req, err := http.NewRequest(op.method, op.address, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if op.gzip {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	if op.forwardQueryParams {
+		req.URL.RawQuery = values.Encode()
+	}
+
+	client := op.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	respBody := resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gzr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gzr.Close() // nolint: errcheck
+		respBody = gzr
+	}
+
+	data, err := ioutil.ReadAll(respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	metricsData := &metricspb.MetricsData{}
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		if err := jsonpb.Unmarshal(bytes.NewReader(data), metricsData); err != nil {
+			return nil, err
+		}
+	} else if err := proto.Unmarshal(data, metricsData); err != nil {
+		return nil, err
+	}
+
+	return convertOTLPToPromFamilies(metricsData.GetResourceMetrics()), nil
+}
@@ -0,0 +1,212 @@
+package metricproxy
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/wrouesnel/reverse_exporter/config"
+)
+
+// nameLabel is the pseudo-label metric relabel rules use to refer to a metric family's name.
+const nameLabel = "__name__"
+
+// compiledRelabelConfig is a config.RelabelConfig with its regex compiled
+// once at config load, since re-compiling it on every scrape would be wasteful.
+type compiledRelabelConfig struct {
+	sourceLabels []string
+	separator    string
+	regex        *regexp.Regexp
+	modulus      uint64
+	targetLabel  string
+	replacement  string
+	action       config.RelabelAction
+}
+
+// compileRelabelConfigs compiles a backend's metric_relabel_configs once so
+// they can be applied cheaply on every scrape thereafter.
+func compileRelabelConfigs(cfgs []config.RelabelConfig) ([]compiledRelabelConfig, error) {
+	compiled := make([]compiledRelabelConfig, 0, len(cfgs))
+
+	for _, cfg := range cfgs {
+		separator := cfg.Separator
+		if separator == "" {
+			separator = ";"
+		}
+
+		action := cfg.Action
+		if action == "" {
+			action = config.RelabelReplace
+		}
+
+		regexStr := cfg.Regex
+		if regexStr == "" {
+			regexStr = "(.*)"
+		}
+
+		re, err := regexp.Compile("^(?:" + regexStr + ")$")
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid metric_relabel_configs regex %q", cfg.Regex)
+		}
+
+		compiled = append(compiled, compiledRelabelConfig{
+			sourceLabels: cfg.SourceLabels,
+			separator:    separator,
+			regex:        re,
+			modulus:      cfg.Modulus,
+			targetLabel:  cfg.TargetLabel,
+			replacement:  cfg.Replacement,
+			action:       action,
+		})
+	}
+
+	return compiled, nil
+}
+
+// applyRelabelConfigs filters and rewrites mfs according to rules, regrouping
+// metrics into families by their (possibly rewritten) name. It returns mfs
+// unmodified if no rules are configured.
+func applyRelabelConfigs(mfs []*dto.MetricFamily, rules []compiledRelabelConfig) []*dto.MetricFamily {
+	if len(rules) == 0 {
+		return mfs
+	}
+
+	families := make(map[string]*dto.MetricFamily)
+	order := make([]string, 0, len(mfs))
+
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			name := mf.GetName()
+			labels := metricLabelMap(name, m)
+			keep := true
+
+			for _, rule := range rules {
+				name, keep = applyRelabelConfig(rule, name, labels)
+				if !keep {
+					break
+				}
+			}
+
+			if !keep {
+				continue
+			}
+
+			m.Label = labelMapToPairs(labels)
+
+			out, found := families[name]
+			if !found {
+				out = &dto.MetricFamily{
+					Name: proto.String(name),
+					Help: mf.Help,
+					Type: mf.Type,
+				}
+				families[name] = out
+				order = append(order, name)
+			}
+			out.Metric = append(out.Metric, m)
+		}
+	}
+
+	result := make([]*dto.MetricFamily, 0, len(order))
+	for _, name := range order {
+		result = append(result, families[name])
+	}
+	return result
+}
+
+// applyRelabelConfig applies a single compiled rule to one metric's name and
+// labels (labels is mutated in place), returning the metric's possibly
+// rewritten name and whether it should still be kept.
+func applyRelabelConfig(rule compiledRelabelConfig, name string, labels map[string]string) (string, bool) {
+	switch rule.action {
+	case config.RelabelKeep:
+		if !rule.regex.MatchString(rule.sourceValue(labels)) {
+			return name, false
+		}
+	case config.RelabelDrop:
+		if rule.regex.MatchString(rule.sourceValue(labels)) {
+			return name, false
+		}
+	case config.RelabelLabelDrop:
+		for label := range labels {
+			if label != nameLabel && rule.regex.MatchString(label) {
+				delete(labels, label)
+			}
+		}
+	case config.RelabelLabelKeep:
+		for label := range labels {
+			if label != nameLabel && !rule.regex.MatchString(label) {
+				delete(labels, label)
+			}
+		}
+	case config.RelabelHashMod:
+		if rule.modulus == 0 || rule.targetLabel == "" {
+			break
+		}
+		sum := fnv.New64a()
+		_, _ = sum.Write([]byte(rule.sourceValue(labels)))
+		labels[rule.targetLabel] = fmt.Sprintf("%d", sum.Sum64()%rule.modulus)
+	case config.RelabelReplace:
+		value := rule.sourceValue(labels)
+		if rule.targetLabel == "" || !rule.regex.MatchString(value) {
+			break
+		}
+		replacement := rule.regex.ReplaceAllString(value, rule.replacement)
+		if rule.targetLabel == nameLabel {
+			name = replacement
+		} else {
+			labels[rule.targetLabel] = replacement
+		}
+	}
+
+	return name, true
+}
+
+// sourceValue joins the configured source label values, used as the regex
+// match input for every action except labeldrop/labelkeep.
+func (c compiledRelabelConfig) sourceValue(labels map[string]string) string {
+	if len(c.sourceLabels) == 0 {
+		return ""
+	}
+
+	values := make([]string, len(c.sourceLabels))
+	for i, label := range c.sourceLabels {
+		values[i] = labels[label]
+	}
+	return strings.Join(values, c.separator)
+}
+
+// metricLabelMap flattens a metric's labels into a map keyed by label name,
+// including the family name under nameLabel.
+func metricLabelMap(name string, m *dto.Metric) map[string]string {
+	labels := make(map[string]string, len(m.GetLabel())+1)
+	labels[nameLabel] = name
+	for _, lp := range m.GetLabel() {
+		labels[lp.GetName()] = lp.GetValue()
+	}
+	return labels
+}
+
+// labelMapToPairs converts a label map back into sorted dto.LabelPair, excluding nameLabel.
+func labelMapToPairs(labels map[string]string) []*dto.LabelPair {
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		if label == nameLabel {
+			continue
+		}
+		names = append(names, label)
+	}
+	sort.Strings(names)
+
+	pairs := make([]*dto.LabelPair, 0, len(names))
+	for _, label := range names {
+		pairs = append(pairs, &dto.LabelPair{Name: proto.String(label), Value: proto.String(labels[label])})
+	}
+	return pairs
+}
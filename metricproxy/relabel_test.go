@@ -0,0 +1,184 @@
+package metricproxy
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/wrouesnel/reverse_exporter/config"
+)
+
+func mustCompileRelabelConfigs(t *testing.T, cfgs []config.RelabelConfig) []compiledRelabelConfig {
+	t.Helper()
+	compiled, err := compileRelabelConfigs(cfgs)
+	if err != nil {
+		t.Fatalf("compileRelabelConfigs: %v", err)
+	}
+	return compiled
+}
+
+func labelValue(m *dto.Metric, name string) (string, bool) {
+	for _, lp := range m.GetLabel() {
+		if lp.GetName() == name {
+			return lp.GetValue(), true
+		}
+	}
+	return "", false
+}
+
+func TestApplyRelabelConfigsKeep(t *testing.T) {
+	mfs := []*dto.MetricFamily{
+		{Name: proto.String("keep_me"), Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(1)}}}},
+		{Name: proto.String("drop_me"), Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(2)}}}},
+	}
+	rules := mustCompileRelabelConfigs(t, []config.RelabelConfig{
+		{SourceLabels: []string{"__name__"}, Regex: "keep_me", Action: config.RelabelKeep},
+	})
+
+	out := applyRelabelConfigs(mfs, rules)
+	if len(out) != 1 || out[0].GetName() != "keep_me" {
+		t.Fatalf("expected only keep_me to survive, got %+v", out)
+	}
+}
+
+func TestApplyRelabelConfigsDrop(t *testing.T) {
+	mfs := []*dto.MetricFamily{
+		{Name: proto.String("keep_me"), Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(1)}}}},
+		{Name: proto.String("drop_me"), Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(2)}}}},
+	}
+	rules := mustCompileRelabelConfigs(t, []config.RelabelConfig{
+		{SourceLabels: []string{"__name__"}, Regex: "drop_me", Action: config.RelabelDrop},
+	})
+
+	out := applyRelabelConfigs(mfs, rules)
+	if len(out) != 1 || out[0].GetName() != "keep_me" {
+		t.Fatalf("expected drop_me to be removed, got %+v", out)
+	}
+}
+
+func TestApplyRelabelConfigsReplace(t *testing.T) {
+	mfs := []*dto.MetricFamily{
+		{
+			Name: proto.String("http_requests"),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{{Name: proto.String("instance"), Value: proto.String("web-01:9100")}},
+				Gauge: &dto.Gauge{Value: proto.Float64(1)},
+			}},
+		},
+	}
+	rules := mustCompileRelabelConfigs(t, []config.RelabelConfig{
+		{
+			SourceLabels: []string{"instance"},
+			Regex:        "([^:]+):.*",
+			Replacement:  "$1",
+			TargetLabel:  "host",
+			Action:       config.RelabelReplace,
+		},
+	})
+
+	out := applyRelabelConfigs(mfs, rules)
+	if len(out) != 1 {
+		t.Fatalf("expected 1 family, got %d", len(out))
+	}
+	if got, ok := labelValue(out[0].Metric[0], "host"); !ok || got != "web-01" {
+		t.Errorf("expected host=web-01, got %q (present=%v)", got, ok)
+	}
+}
+
+func TestApplyRelabelConfigsReplaceRenamesMetric(t *testing.T) {
+	mfs := []*dto.MetricFamily{
+		{Name: proto.String("old_name"), Metric: []*dto.Metric{{Gauge: &dto.Gauge{Value: proto.Float64(1)}}}},
+	}
+	rules := mustCompileRelabelConfigs(t, []config.RelabelConfig{
+		{SourceLabels: []string{"__name__"}, Regex: "old_name", Replacement: "new_name", TargetLabel: "__name__", Action: config.RelabelReplace},
+	})
+
+	out := applyRelabelConfigs(mfs, rules)
+	if len(out) != 1 || out[0].GetName() != "new_name" {
+		t.Fatalf("expected metric renamed to new_name, got %+v", out)
+	}
+}
+
+func TestApplyRelabelConfigsLabelDrop(t *testing.T) {
+	mfs := []*dto.MetricFamily{
+		{
+			Name: proto.String("metric"),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{
+					{Name: proto.String("keep"), Value: proto.String("1")},
+					{Name: proto.String("internal_tmp"), Value: proto.String("2")},
+				},
+				Gauge: &dto.Gauge{Value: proto.Float64(1)},
+			}},
+		},
+	}
+	rules := mustCompileRelabelConfigs(t, []config.RelabelConfig{
+		{Regex: "internal_.*", Action: config.RelabelLabelDrop},
+	})
+
+	out := applyRelabelConfigs(mfs, rules)
+	if _, ok := labelValue(out[0].Metric[0], "internal_tmp"); ok {
+		t.Errorf("expected internal_tmp label to be dropped")
+	}
+	if _, ok := labelValue(out[0].Metric[0], "keep"); !ok {
+		t.Errorf("expected keep label to survive")
+	}
+}
+
+func TestApplyRelabelConfigsLabelKeep(t *testing.T) {
+	mfs := []*dto.MetricFamily{
+		{
+			Name: proto.String("metric"),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{
+					{Name: proto.String("keep"), Value: proto.String("1")},
+					{Name: proto.String("drop"), Value: proto.String("2")},
+				},
+				Gauge: &dto.Gauge{Value: proto.Float64(1)},
+			}},
+		},
+	}
+	rules := mustCompileRelabelConfigs(t, []config.RelabelConfig{
+		{Regex: "keep", Action: config.RelabelLabelKeep},
+	})
+
+	out := applyRelabelConfigs(mfs, rules)
+	if _, ok := labelValue(out[0].Metric[0], "drop"); ok {
+		t.Errorf("expected drop label to be removed")
+	}
+	if _, ok := labelValue(out[0].Metric[0], "keep"); !ok {
+		t.Errorf("expected keep label to survive")
+	}
+}
+
+func TestApplyRelabelConfigsHashMod(t *testing.T) {
+	mfs := []*dto.MetricFamily{
+		{
+			Name: proto.String("metric"),
+			Metric: []*dto.Metric{{
+				Label: []*dto.LabelPair{{Name: proto.String("id"), Value: proto.String("abc")}},
+				Gauge: &dto.Gauge{Value: proto.Float64(1)},
+			}},
+		},
+	}
+	rules := mustCompileRelabelConfigs(t, []config.RelabelConfig{
+		{SourceLabels: []string{"id"}, Modulus: 16, TargetLabel: "shard", Action: config.RelabelHashMod},
+	})
+
+	out := applyRelabelConfigs(mfs, rules)
+	shard, ok := labelValue(out[0].Metric[0], "shard")
+	if !ok {
+		t.Fatalf("expected shard label to be set")
+	}
+	if shard == "" {
+		t.Errorf("expected non-empty shard value")
+	}
+}
+
+func TestApplyRelabelConfigsNoRulesReturnsInputUnmodified(t *testing.T) {
+	mfs := []*dto.MetricFamily{{Name: proto.String("metric")}}
+	if out := applyRelabelConfigs(mfs, nil); len(out) != 1 || out[0] != mfs[0] {
+		t.Fatalf("expected applyRelabelConfigs with no rules to return the input slice verbatim")
+	}
+}
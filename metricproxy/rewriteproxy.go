@@ -0,0 +1,66 @@
+package metricproxy
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/common/model"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// reverseProxyNameLabel is the label added to identify which exporter a metric family came from.
+const reverseProxyNameLabel = model.LabelName("exporter")
+
+// ensure rewriteProxy implements MetricProxy
+var _ MetricProxy = &rewriteProxy{}
+
+// rewriteProxy wraps a MetricProxy, adding a fixed set of labels to every metric it returns.
+// Each rewriteProxy is owned by exactly one namedBackend - a config reload
+// always rebuilds every endpoint's backends from scratch and closes the
+// entire previous generation, so there is no sharing to reference-count.
+type rewriteProxy struct {
+	proxy          MetricProxy
+	labels         model.LabelSet
+	relabelConfigs []compiledRelabelConfig
+}
+
+// Release closes the underlying MetricProxy, if it supports shutting down its
+// own goroutines.
+func (rp *rewriteProxy) Release() error {
+	if closer, ok := rp.proxy.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Scrape implements MetricProxy.
+func (rp *rewriteProxy) Scrape(ctx context.Context, values url.Values) ([]*dto.MetricFamily, error) {
+	mfs, err := rp.proxy.Scrape(ctx, values)
+	if err != nil {
+		return mfs, err
+	}
+
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			for name, value := range rp.labels {
+				m.Label = append(m.Label, &dto.LabelPair{
+					Name:  proto.String(string(name)),
+					Value: proto.String(string(value)),
+				})
+			}
+		}
+	}
+
+	return applyRelabelConfigs(mfs, rp.relabelConfigs), nil
+}
+
+// SelfMetrics forwards to the wrapped proxy if it contributes its own
+// synthetic metrics (e.g. a circuitBreakerProxy's state gauge).
+func (rp *rewriteProxy) SelfMetrics() []*dto.MetricFamily {
+	if provider, ok := rp.proxy.(interface{ SelfMetrics() []*dto.MetricFamily }); ok {
+		return provider.SelfMetrics()
+	}
+	return nil
+}
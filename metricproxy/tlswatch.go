@@ -0,0 +1,86 @@
+package metricproxy
+
+import (
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+)
+
+// tlsWatcher lazily starts a single fsnotify.Watcher shared by every netProxy
+// configured with TLS.AutoReload, keyed by the certificate/key/CA file path.
+// This mirrors the root CA rotation pattern used by etcd's transport package,
+// but multiplexed across however many backends happen to share a bundle.
+var (
+	tlsWatcherOnce      sync.Once
+	tlsWatcherInstance  *fsnotify.Watcher
+	tlsWatcherMtx       sync.Mutex
+	tlsWatcherCallbacks = make(map[string][]func())
+)
+
+// watchTLSFiles registers onChange to be invoked whenever any of the named
+// files change on disk. Empty paths are ignored.
+func watchTLSFiles(onChange func(), paths ...string) {
+	tlsWatcherOnce.Do(startTLSWatcher)
+
+	tlsWatcherMtx.Lock()
+	defer tlsWatcherMtx.Unlock()
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		if _, watching := tlsWatcherCallbacks[path]; !watching {
+			if tlsWatcherInstance == nil {
+				continue
+			}
+			if err := tlsWatcherInstance.Add(path); err != nil {
+				zap.L().Error("Could not watch TLS file for changes",
+					zap.String("file", path), zap.Error(err))
+				continue
+			}
+		}
+
+		tlsWatcherCallbacks[path] = append(tlsWatcherCallbacks[path], onChange)
+	}
+}
+
+// startTLSWatcher initializes the process-wide fsnotify watcher. Called at most once.
+func startTLSWatcher() {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		zap.L().Error("Could not start TLS file watcher, certificate auto-reload disabled", zap.Error(err))
+		return
+	}
+	tlsWatcherInstance = w
+
+	go func() {
+		for event := range w.Events {
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				// Certificate rotation tooling (cert-manager, acme clients,
+				// kubelet, ...) almost universally rotates by writing a new
+				// file and renaming it over the watched path, which fires
+				// Remove/Rename rather than Write and invalidates the
+				// inotify watch on that path. Re-add it so later rotations
+				// are still observed, mirroring etcd's transport package.
+				if err := w.Add(event.Name); err != nil {
+					zap.L().Error("Could not re-watch TLS file after rotation",
+						zap.String("file", event.Name), zap.Error(err))
+				}
+			}
+
+			tlsWatcherMtx.Lock()
+			callbacks := append([]func(){}, tlsWatcherCallbacks[event.Name]...)
+			tlsWatcherMtx.Unlock()
+
+			for _, cb := range callbacks {
+				cb()
+			}
+		}
+	}()
+}
@@ -0,0 +1,5 @@
+// Package version holds the application version, overridden via -ldflags at release build time.
+package version
+
+// Version is the current application version.
+var Version = "dev"